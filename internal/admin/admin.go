@@ -0,0 +1,324 @@
+// Package admin implements a small, HTTP Basic Auth protected admin subtree for
+// managing the video catalog: uploading new files, deleting them, and renaming them.
+// It writes videos.json under a mutex and relies on the caller's fsnotify watcher to
+// propagate the change into the shared catalog.Store.
+package admin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/temirov/web_video/internal/catalog"
+)
+
+// missingCredentialsDelay slows down brute-force attempts against the admin endpoints.
+const missingCredentialsDelay = 3 * time.Second
+
+// errDestinationExists is returned by renameVideo when the requested destination file
+// name is already taken, so handleRename can surface a specific conflict message.
+var errDestinationExists = errors.New("destination file name already exists")
+
+// Credentials holds the Basic Auth username and password required to reach /admin.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Handler serves the /admin subtree: a video list/management page plus upload,
+// delete, and rename actions that rewrite videos.json.
+type Handler struct {
+	credentials     Credentials
+	staticDirectory string
+	videosJSONPath  string
+	adminTemplate   *template.Template
+	videoStore      *catalog.Store
+	writeMutex      sync.Mutex
+}
+
+// adminPageData is the payload passed into admin.html.
+type adminPageData struct {
+	Videos []catalog.Video
+	Error  string
+}
+
+// NewHandler parses admin.html out of templatesFS and returns a Handler ready to be mounted.
+// templatesFS is rooted at the templates directory, whether that is an embedded FS or an
+// os.DirFS pointed at an on-disk override.
+func NewHandler(credentials Credentials, staticDirectory string, videosJSONPath string, templatesFS fs.FS, videoStore *catalog.Store) (*Handler, error) {
+	parsedTemplate, parseError := template.ParseFS(templatesFS, "admin.html")
+	if parseError != nil {
+		return nil, fmt.Errorf("parse admin template: %w", parseError)
+	}
+
+	return &Handler{
+		credentials:     credentials,
+		staticDirectory: staticDirectory,
+		videosJSONPath:  videosJSONPath,
+		adminTemplate:   parsedTemplate,
+		videoStore:      videoStore,
+	}, nil
+}
+
+// RegisterRoutes mounts the admin endpoints on mux, wrapping each with Basic Auth.
+func (handler *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin", handler.requireBasicAuth(handler.handleIndex))
+	mux.HandleFunc("/admin/", handler.requireBasicAuth(handler.handleIndex))
+	mux.HandleFunc("/admin/upload", handler.requireBasicAuth(handler.handleUpload))
+	mux.HandleFunc("/admin/delete", handler.requireBasicAuth(handler.handleDelete))
+	mux.HandleFunc("/admin/rename", handler.requireBasicAuth(handler.handleRename))
+}
+
+// requireBasicAuth wraps next with constant-time Basic Auth verification, sleeping
+// missingCredentialsDelay before rejecting a request with absent or wrong credentials.
+func (handler *Handler) requireBasicAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		suppliedUsername, suppliedPassword, hasBasicAuth := request.BasicAuth()
+
+		usernameMatches := hasBasicAuth && subtle.ConstantTimeCompare([]byte(suppliedUsername), []byte(handler.credentials.Username)) == 1
+		passwordMatches := hasBasicAuth && subtle.ConstantTimeCompare([]byte(suppliedPassword), []byte(handler.credentials.Password)) == 1
+
+		if !usernameMatches || !passwordMatches {
+			time.Sleep(missingCredentialsDelay)
+			responseWriter.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(responseWriter, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(responseWriter, request)
+	}
+}
+
+// handleIndex renders admin.html with the current catalog.
+func (handler *Handler) handleIndex(responseWriter http.ResponseWriter, request *http.Request) {
+	pageData := adminPageData{Videos: handler.videoStore.Load()}
+
+	if renderError := handler.adminTemplate.Execute(responseWriter, pageData); renderError != nil {
+		http.Error(responseWriter, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("admin: template execution error: %v", renderError)
+	}
+}
+
+// renderAdminError re-renders admin.html with message set as the page's Error banner,
+// so failures surface in the admin UI itself rather than as a bare text/plain response.
+func (handler *Handler) renderAdminError(responseWriter http.ResponseWriter, statusCode int, message string) {
+	pageData := adminPageData{Videos: handler.videoStore.Load(), Error: message}
+
+	responseWriter.WriteHeader(statusCode)
+	if renderError := handler.adminTemplate.Execute(responseWriter, pageData); renderError != nil {
+		http.Error(responseWriter, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("admin: template execution error: %v", renderError)
+	}
+}
+
+// handleUpload accepts a multipart form with a "file" part plus "title" and
+// "description" fields, streams the upload to a temp file, and appends it to videos.json.
+func (handler *Handler) handleUpload(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(responseWriter, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	const maxUploadBytes = 4 << 30 // 4 GiB
+	request.Body = http.MaxBytesReader(responseWriter, request.Body, maxUploadBytes)
+
+	uploadedFile, uploadedFileHeader, formError := request.FormFile("file")
+	if formError != nil {
+		handler.renderAdminError(responseWriter, http.StatusBadRequest, fmt.Sprintf("missing file: %v", formError))
+		return
+	}
+	defer uploadedFile.Close()
+
+	sanitizedFileName, isBareFileName := catalog.SanitizeFileName(uploadedFileHeader.Filename)
+	if !isBareFileName {
+		handler.renderAdminError(responseWriter, http.StatusBadRequest, "invalid file name")
+		return
+	}
+
+	videosDirectory := filepath.Join(handler.staticDirectory, "videos")
+	destinationPath := filepath.Join(videosDirectory, sanitizedFileName)
+
+	temporaryFile, createError := os.CreateTemp(videosDirectory, ".upload-*.tmp")
+	if createError != nil {
+		log.Printf("admin: failed to create temp upload file: %v", createError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to save uploaded file")
+		return
+	}
+	temporaryFilePath := temporaryFile.Name()
+
+	if _, copyError := io.Copy(temporaryFile, uploadedFile); copyError != nil {
+		temporaryFile.Close()
+		os.Remove(temporaryFilePath)
+		log.Printf("admin: failed to write uploaded file: %v", copyError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to save uploaded file")
+		return
+	}
+	if closeError := temporaryFile.Close(); closeError != nil {
+		os.Remove(temporaryFilePath)
+		log.Printf("admin: failed to close uploaded file: %v", closeError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to save uploaded file")
+		return
+	}
+
+	if renameError := os.Rename(temporaryFilePath, destinationPath); renameError != nil {
+		os.Remove(temporaryFilePath)
+		log.Printf("admin: failed to move uploaded file into place: %v", renameError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to save uploaded file")
+		return
+	}
+
+	newVideo := catalog.Video{
+		Title:       request.FormValue("title"),
+		Description: request.FormValue("description"),
+		FileName:    sanitizedFileName,
+	}
+
+	if updateError := handler.updateVideosJSON(func(currentVideos []catalog.Video) []catalog.Video {
+		for index, existingVideo := range currentVideos {
+			if existingVideo.FileName == newVideo.FileName {
+				currentVideos[index] = newVideo
+				return currentVideos
+			}
+		}
+		return append(currentVideos, newVideo)
+	}); updateError != nil {
+		log.Printf("admin: failed to update videos.json after upload: %v", updateError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to update video catalog")
+		return
+	}
+
+	http.Redirect(responseWriter, request, "/admin", http.StatusSeeOther)
+}
+
+// handleDelete removes the video file named by the "file" query parameter and its videos.json entry.
+func (handler *Handler) handleDelete(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(responseWriter, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sanitizedFileName, isBareFileName := catalog.SanitizeFileName(request.URL.Query().Get("file"))
+	if !isBareFileName {
+		handler.renderAdminError(responseWriter, http.StatusBadRequest, "invalid file name")
+		return
+	}
+
+	if updateError := handler.updateVideosJSON(func(currentVideos []catalog.Video) []catalog.Video {
+		remainingVideos := make([]catalog.Video, 0, len(currentVideos))
+		for _, existingVideo := range currentVideos {
+			if existingVideo.FileName != sanitizedFileName {
+				remainingVideos = append(remainingVideos, existingVideo)
+			}
+		}
+		return remainingVideos
+	}); updateError != nil {
+		log.Printf("admin: failed to update videos.json after delete: %v", updateError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to update video catalog")
+		return
+	}
+
+	videoPath := filepath.Join(handler.staticDirectory, "videos", sanitizedFileName)
+	if removeError := os.Remove(videoPath); removeError != nil && !os.IsNotExist(removeError) {
+		log.Printf("admin: failed to remove video file %s: %v", videoPath, removeError)
+	}
+
+	http.Redirect(responseWriter, request, "/admin", http.StatusSeeOther)
+}
+
+// handleRename renames a video file on disk and its videos.json entry, from the "from"
+// query parameter to the "to" query parameter.
+func (handler *Handler) handleRename(responseWriter http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(responseWriter, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sanitizedFromFileName, fromIsBare := catalog.SanitizeFileName(request.URL.Query().Get("from"))
+	sanitizedToFileName, toIsBare := catalog.SanitizeFileName(request.URL.Query().Get("to"))
+	if !fromIsBare || !toIsBare {
+		handler.renderAdminError(responseWriter, http.StatusBadRequest, "invalid file name")
+		return
+	}
+
+	videosDirectory := filepath.Join(handler.staticDirectory, "videos")
+	if renameError := handler.renameVideo(videosDirectory, sanitizedFromFileName, sanitizedToFileName); renameError != nil {
+		if errors.Is(renameError, errDestinationExists) {
+			handler.renderAdminError(responseWriter, http.StatusConflict, fmt.Sprintf("%s already exists", sanitizedToFileName))
+			return
+		}
+		log.Printf("admin: failed to rename video file: %v", renameError)
+		handler.renderAdminError(responseWriter, http.StatusInternalServerError, "failed to rename video file")
+		return
+	}
+
+	http.Redirect(responseWriter, request, "/admin", http.StatusSeeOther)
+}
+
+// renameVideo locks writeMutex and, in one critical section, verifies that
+// sanitizedToFileName is free both on disk and in videos.json (returning
+// errDestinationExists if not), renames the file, and rewrites the catalog entry. Doing
+// the existence checks and the rename under the same lock that guards videos.json writes
+// closes the race where a concurrent upload or rename could claim sanitizedToFileName
+// between a separate check and the os.Rename call.
+func (handler *Handler) renameVideo(videosDirectory string, sanitizedFromFileName string, sanitizedToFileName string) error {
+	handler.writeMutex.Lock()
+	defer handler.writeMutex.Unlock()
+
+	existingVideos, readError := catalog.ReadRaw(handler.videosJSONPath)
+	if readError != nil && !os.IsNotExist(readError) {
+		return fmt.Errorf("read %s: %w", handler.videosJSONPath, readError)
+	}
+
+	for _, existingVideo := range existingVideos {
+		if existingVideo.FileName == sanitizedToFileName {
+			return errDestinationExists
+		}
+	}
+
+	if _, statError := os.Stat(filepath.Join(videosDirectory, sanitizedToFileName)); statError == nil {
+		return errDestinationExists
+	} else if !os.IsNotExist(statError) {
+		return fmt.Errorf("stat destination file: %w", statError)
+	}
+
+	if renameError := os.Rename(filepath.Join(videosDirectory, sanitizedFromFileName), filepath.Join(videosDirectory, sanitizedToFileName)); renameError != nil {
+		return fmt.Errorf("rename video file: %w", renameError)
+	}
+
+	for index, existingVideo := range existingVideos {
+		if existingVideo.FileName == sanitizedFromFileName {
+			existingVideos[index].FileName = sanitizedToFileName
+		}
+	}
+
+	return catalog.WriteAtomic(handler.videosJSONPath, existingVideos)
+}
+
+// updateVideosJSON reads videos.json straight from disk, applies mutateVideos under
+// writeMutex, and writes the result back atomically. It deliberately reads the file itself
+// rather than handler.videoStore.Load(), since the store is only refreshed asynchronously
+// by the fsnotify watcher after a debounce: two admin writes within that window would
+// otherwise both read the same stale snapshot and the second write would clobber the first.
+// The watcher still picks up the written change afterwards and refreshes the shared store.
+func (handler *Handler) updateVideosJSON(mutateVideos func([]catalog.Video) []catalog.Video) error {
+	handler.writeMutex.Lock()
+	defer handler.writeMutex.Unlock()
+
+	existingVideos, readError := catalog.ReadRaw(handler.videosJSONPath)
+	if readError != nil && !os.IsNotExist(readError) {
+		return fmt.Errorf("read %s: %w", handler.videosJSONPath, readError)
+	}
+
+	updatedVideos := mutateVideos(existingVideos)
+
+	return catalog.WriteAtomic(handler.videosJSONPath, updatedVideos)
+}
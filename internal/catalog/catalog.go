@@ -0,0 +1,212 @@
+// Package catalog owns the validated video list: loading it from videos.json,
+// sanitizing file names, and holding the current snapshot behind an atomic.Value
+// so readers never observe a partially-updated slice.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Video represents a single video entry from the JSON file.
+type Video struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	FileName    string `json:"fileName"`
+}
+
+// Store holds the current validated video slice and allows atomic swaps.
+type Store struct {
+	value atomic.Value
+}
+
+// NewStore returns a Store pre-populated with the given videos.
+func NewStore(initialVideos []Video) *Store {
+	store := &Store{}
+	store.Store(initialVideos)
+	return store
+}
+
+// Load returns the current validated video slice.
+func (store *Store) Load() []Video {
+	loadedInterface := store.value.Load()
+	loadedVideos, castOK := loadedInterface.([]Video)
+	if !castOK {
+		return nil
+	}
+	return loadedVideos
+}
+
+// Store atomically replaces the current validated video slice.
+func (store *Store) Store(videos []Video) {
+	store.value.Store(videos)
+}
+
+// SanitizeFileName returns the base name of fileName and reports whether it was
+// already a bare file name, i.e. contained no path separators or traversal.
+func SanitizeFileName(fileName string) (string, bool) {
+	baseFileName := filepath.Base(fileName)
+	return baseFileName, baseFileName == fileName && fileName != "" && fileName != "." && fileName != string(filepath.Separator)
+}
+
+// LoadAndValidate reads the JSON file at videosJSONPath, validates each entry,
+// and returns the sanitized slice of Video objects. Invalid or missing video files are skipped with a warning.
+func LoadAndValidate(videosJSONPath string, staticVideosDirectory string) ([]Video, error) {
+	contentBytes, readError := os.ReadFile(videosJSONPath)
+	if readError != nil {
+		return nil, readError
+	}
+
+	var rawVideos []Video
+	if unmarshalError := json.Unmarshal(contentBytes, &rawVideos); unmarshalError != nil {
+		return nil, unmarshalError
+	}
+
+	validatedVideos := make([]Video, 0, len(rawVideos))
+	for _, candidateVideo := range rawVideos {
+		if strings.TrimSpace(candidateVideo.FileName) == "" {
+			log.Printf("warning: skipping video with empty fileName: title=%q", candidateVideo.Title)
+			continue
+		}
+
+		baseFileName, isBareFileName := SanitizeFileName(candidateVideo.FileName)
+		if !isBareFileName {
+			log.Printf("warning: skipping video with disallowed path in fileName: %q", candidateVideo.FileName)
+			continue
+		}
+
+		candidatePath := filepath.Join(staticVideosDirectory, "videos", baseFileName)
+		if _, statError := os.Stat(candidatePath); os.IsNotExist(statError) {
+			log.Printf("warning: video file does not exist, skipping: %s", candidatePath)
+			continue
+		} else if statError != nil {
+			log.Printf("warning: unable to stat video file %s: %v (skipping)", candidatePath, statError)
+			continue
+		}
+
+		if strings.TrimSpace(candidateVideo.Title) == "" {
+			log.Printf("warning: skipping video with empty title for fileName=%q", candidateVideo.FileName)
+			continue
+		}
+		if strings.TrimSpace(candidateVideo.Description) == "" {
+			log.Printf("warning: skipping video with empty description for fileName=%q", candidateVideo.FileName)
+			continue
+		}
+
+		validatedVideos = append(validatedVideos, candidateVideo)
+	}
+
+	return validatedVideos, nil
+}
+
+// Rescan walks staticDirectory/videos, reconciles it against the entries already recorded
+// in videosJSONPath (keeping their title/description), adds placeholder entries for new
+// files, drops entries whose files vanished, and writes the result back atomically. It
+// does not apply the title/description validation LoadAndValidate does, since its job is
+// to repair the catalog from the filesystem rather than judge existing entries.
+func Rescan(videosJSONPath string, staticDirectory string) ([]Video, error) {
+	existingVideos, readError := ReadRaw(videosJSONPath)
+	if readError != nil && !os.IsNotExist(readError) {
+		return nil, fmt.Errorf("read %s: %w", videosJSONPath, readError)
+	}
+
+	existingByFileName := make(map[string]Video, len(existingVideos))
+	for _, existingVideo := range existingVideos {
+		existingByFileName[existingVideo.FileName] = existingVideo
+	}
+
+	videosDirectory := filepath.Join(staticDirectory, "videos")
+	directoryEntries, readDirError := os.ReadDir(videosDirectory)
+	if readDirError != nil {
+		return nil, fmt.Errorf("read %s: %w", videosDirectory, readDirError)
+	}
+
+	onDiskFileNames := make(map[string]struct{}, len(directoryEntries))
+	reconciledVideos := make([]Video, 0, len(directoryEntries))
+	for _, directoryEntry := range directoryEntries {
+		if directoryEntry.IsDir() {
+			continue
+		}
+
+		fileName := directoryEntry.Name()
+		onDiskFileNames[fileName] = struct{}{}
+
+		if existingVideo, found := existingByFileName[fileName]; found {
+			reconciledVideos = append(reconciledVideos, existingVideo)
+			continue
+		}
+
+		log.Printf("rescan: adding new video with placeholder metadata: %s", fileName)
+		reconciledVideos = append(reconciledVideos, Video{
+			Title:       fileName,
+			Description: "Added by -rescan; edit videos.json to set a real title and description.",
+			FileName:    fileName,
+		})
+	}
+
+	for fileName := range existingByFileName {
+		if _, stillOnDisk := onDiskFileNames[fileName]; !stillOnDisk {
+			log.Printf("rescan: dropping video whose file no longer exists: %s", fileName)
+		}
+	}
+
+	sort.Slice(reconciledVideos, func(i, j int) bool {
+		return reconciledVideos[i].FileName < reconciledVideos[j].FileName
+	})
+
+	if writeError := WriteAtomic(videosJSONPath, reconciledVideos); writeError != nil {
+		return nil, writeError
+	}
+
+	return reconciledVideos, nil
+}
+
+// ReadRaw reads and decodes videosJSONPath without validating entries against the filesystem.
+func ReadRaw(videosJSONPath string) ([]Video, error) {
+	contentBytes, readError := os.ReadFile(videosJSONPath)
+	if readError != nil {
+		return nil, readError
+	}
+
+	var videos []Video
+	if unmarshalError := json.Unmarshal(contentBytes, &videos); unmarshalError != nil {
+		return nil, unmarshalError
+	}
+	return videos, nil
+}
+
+// WriteAtomic marshals videos as indented JSON and writes them to videosJSONPath via a
+// temp-file-then-rename so readers (including the fsnotify watcher) never see a partial write.
+func WriteAtomic(videosJSONPath string, videos []Video) error {
+	encodedBytes, marshalError := json.MarshalIndent(videos, "", "  ")
+	if marshalError != nil {
+		return fmt.Errorf("marshal videos: %w", marshalError)
+	}
+
+	temporaryFile, createError := os.CreateTemp(filepath.Dir(videosJSONPath), ".videos-*.json.tmp")
+	if createError != nil {
+		return fmt.Errorf("create temp file: %w", createError)
+	}
+	temporaryFilePath := temporaryFile.Name()
+	defer os.Remove(temporaryFilePath)
+
+	if _, writeError := temporaryFile.Write(encodedBytes); writeError != nil {
+		temporaryFile.Close()
+		return fmt.Errorf("write temp file: %w", writeError)
+	}
+	if closeError := temporaryFile.Close(); closeError != nil {
+		return fmt.Errorf("close temp file: %w", closeError)
+	}
+
+	if renameError := os.Rename(temporaryFilePath, videosJSONPath); renameError != nil {
+		return fmt.Errorf("rename temp file into place: %w", renameError)
+	}
+
+	return nil
+}
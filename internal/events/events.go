@@ -0,0 +1,97 @@
+// Package events implements a small Server-Sent Events pub/sub hub used to push
+// catalog updates to connected browsers as soon as watchVideosJSON reloads them.
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// heartbeatInterval keeps intermediary proxies from closing an idle SSE connection.
+const heartbeatInterval = 15 * time.Second
+
+// eventName is the SSE event type dispatched on every catalog update.
+const eventName = "videos-updated"
+
+// Hub fans catalog update payloads out to every currently connected SSE client.
+type Hub struct {
+	subscribersMutex sync.RWMutex
+	subscribers      map[chan []byte]struct{}
+}
+
+// NewHub returns an empty Hub ready to accept subscribers.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[chan []byte]struct{})}
+}
+
+// Broadcast sends payload to every current subscriber, dropping it for any subscriber
+// whose channel is full rather than blocking the caller.
+func (hub *Hub) Broadcast(payload []byte) {
+	hub.subscribersMutex.RLock()
+	defer hub.subscribersMutex.RUnlock()
+
+	for subscriber := range hub.subscribers {
+		select {
+		case subscriber <- payload:
+		default:
+			// Slow subscriber; it will catch up on the next broadcast.
+		}
+	}
+}
+
+// subscribe registers a new subscriber channel and returns it.
+func (hub *Hub) subscribe() chan []byte {
+	subscriber := make(chan []byte, 1)
+
+	hub.subscribersMutex.Lock()
+	hub.subscribers[subscriber] = struct{}{}
+	hub.subscribersMutex.Unlock()
+
+	return subscriber
+}
+
+// unsubscribe removes subscriber from the hub.
+func (hub *Hub) unsubscribe(subscriber chan []byte) {
+	hub.subscribersMutex.Lock()
+	delete(hub.subscribers, subscriber)
+	hub.subscribersMutex.Unlock()
+}
+
+// ServeHTTP upgrades the request to a Server-Sent Events stream, forwarding every
+// broadcast payload as a videos-updated event until the client disconnects.
+func (hub *Hub) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	flusher, canFlush := responseWriter.(http.Flusher)
+	if !canFlush {
+		http.Error(responseWriter, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "text/event-stream")
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	responseWriter.Header().Set("Connection", "keep-alive")
+	responseWriter.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	subscriber := hub.subscribe()
+	defer hub.unsubscribe(subscriber)
+
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	for {
+		select {
+		case payload := <-subscriber:
+			fmt.Fprintf(responseWriter, "event: %s\ndata: %s\n\n", eventName, payload)
+			flusher.Flush()
+
+		case <-heartbeatTicker.C:
+			fmt.Fprint(responseWriter, ": heartbeat\n\n")
+			flusher.Flush()
+
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
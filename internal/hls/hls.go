@@ -0,0 +1,260 @@
+// Package hls serves adaptive-bitrate playback of the existing MP4 catalog by
+// transcoding on demand with ffmpeg. Each active player gets its own Manager: an
+// ffmpeg process writing MPEG-TS segments and an index.m3u8 into a temp directory,
+// which the Handler streams back chunk by chunk as ffmpeg produces them.
+package hls
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temirov/web_video/internal/catalog"
+)
+
+// chunkPollInterval is how often we check for a not-yet-written segment file.
+const chunkPollInterval = 100 * time.Millisecond
+
+// chunkWaitTimeout bounds how long a request waits for ffmpeg to produce a chunk.
+const chunkWaitTimeout = 15 * time.Second
+
+// reapInterval is how often the idle reaper scans for expired managers.
+const reapInterval = 10 * time.Second
+
+// errTooManyManagers is returned by getOrCreateManager when maxActiveManagers active
+// transcodes are already running, so ServeHTTP can reject the request instead of
+// spawning an unbounded number of concurrent ffmpeg processes.
+var errTooManyManagers = errors.New("too many active HLS transcodes")
+
+// Manager owns one ffmpeg transcode: the running process, its segment directory,
+// and the time it was last read from.
+type Manager struct {
+	command          *exec.Cmd
+	segmentDirectory string
+
+	lastAccessMutex sync.Mutex
+	lastAccess      time.Time
+}
+
+// touch records that the manager was just used, keeping it alive past the idle TTL.
+func (manager *Manager) touch() {
+	manager.lastAccessMutex.Lock()
+	manager.lastAccess = time.Now()
+	manager.lastAccessMutex.Unlock()
+}
+
+// idleSince reports how long it has been since the manager was last used.
+func (manager *Manager) idleSince() time.Duration {
+	manager.lastAccessMutex.Lock()
+	defer manager.lastAccessMutex.Unlock()
+	return time.Since(manager.lastAccess)
+}
+
+// Handler serves /hls/<videoFileName>/<streamID>/<chunk> requests, spawning or
+// reusing a per-stream Manager and terminating idle ones in the background.
+type Handler struct {
+	staticDirectory  string
+	ffmpegPath       string
+	idleTTL          time.Duration
+	maxActiveStreams int
+
+	managersMutex sync.RWMutex
+	managers      map[string]*Manager
+
+	closeRequests chan string
+}
+
+// NewHandler starts the idle-reaper and close-consumer goroutines and returns a ready Handler.
+// maxActiveStreams caps how many concurrent ffmpeg transcodes getOrCreateManager will spawn;
+// requests for a new stream beyond that cap are rejected rather than queued.
+func NewHandler(staticDirectory string, ffmpegPath string, idleTTL time.Duration, maxActiveStreams int) *Handler {
+	handler := &Handler{
+		staticDirectory:  staticDirectory,
+		ffmpegPath:       ffmpegPath,
+		idleTTL:          idleTTL,
+		maxActiveStreams: maxActiveStreams,
+		managers:         make(map[string]*Manager),
+		closeRequests:    make(chan string, 16),
+	}
+
+	go handler.reapIdleManagers()
+	go handler.consumeCloseRequests()
+
+	return handler
+}
+
+// ServeHTTP expects a path of the form <videoFileName>/<streamID>/<chunk>, as left after
+// stripping the /hls/ prefix.
+func (handler *Handler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	pathSegments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	if len(pathSegments) != 3 {
+		http.NotFound(responseWriter, request)
+		return
+	}
+
+	videoFileName, isBareVideoFileName := catalog.SanitizeFileName(pathSegments[0])
+	streamID, isBareStreamID := catalog.SanitizeFileName(pathSegments[1])
+	chunkFileName, isBareChunkFileName := catalog.SanitizeFileName(pathSegments[2])
+	if !isBareVideoFileName || !isBareStreamID || !isBareChunkFileName {
+		http.Error(responseWriter, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	manager, managerError := handler.getOrCreateManager(streamID, videoFileName)
+	if errors.Is(managerError, os.ErrNotExist) {
+		http.NotFound(responseWriter, request)
+		return
+	}
+	if errors.Is(managerError, errTooManyManagers) {
+		http.Error(responseWriter, "too many active transcodes, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	if managerError != nil {
+		http.Error(responseWriter, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("hls: failed to start transcode for %s/%s: %v", videoFileName, streamID, managerError)
+		return
+	}
+	manager.touch()
+
+	chunkPath := filepath.Join(manager.segmentDirectory, chunkFileName)
+	if waitError := waitForFile(chunkPath, chunkWaitTimeout); waitError != nil {
+		http.Error(responseWriter, "chunk not available", http.StatusGatewayTimeout)
+		log.Printf("hls: timed out waiting for %s: %v", chunkPath, waitError)
+		return
+	}
+
+	http.ServeFile(responseWriter, request, chunkPath)
+}
+
+// getOrCreateManager returns the existing Manager for streamID, or spawns a new ffmpeg
+// transcode of videoFileName and registers it under streamID.
+func (handler *Handler) getOrCreateManager(streamID string, videoFileName string) (*Manager, error) {
+	handler.managersMutex.RLock()
+	existingManager, exists := handler.managers[streamID]
+	handler.managersMutex.RUnlock()
+	if exists {
+		return existingManager, nil
+	}
+
+	handler.managersMutex.Lock()
+	defer handler.managersMutex.Unlock()
+
+	if existingManager, exists := handler.managers[streamID]; exists {
+		return existingManager, nil
+	}
+
+	if len(handler.managers) >= handler.maxActiveStreams {
+		return nil, errTooManyManagers
+	}
+
+	sourcePath := filepath.Join(handler.staticDirectory, "videos", videoFileName)
+	if _, statError := os.Stat(sourcePath); statError != nil {
+		return nil, fmt.Errorf("stat source video: %w", statError)
+	}
+
+	segmentDirectory, mkdirError := os.MkdirTemp("", "web_video-hls-*")
+	if mkdirError != nil {
+		return nil, fmt.Errorf("create segment directory: %w", mkdirError)
+	}
+
+	playlistPath := filepath.Join(segmentDirectory, "index.m3u8")
+	segmentPattern := filepath.Join(segmentDirectory, "segment%03d.ts")
+
+	command := exec.Command(handler.ffmpegPath,
+		"-i", sourcePath,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", "4",
+		"-hls_list_size", "0",
+		"-hls_segment_filename", segmentPattern,
+		playlistPath,
+	)
+
+	if startError := command.Start(); startError != nil {
+		os.RemoveAll(segmentDirectory)
+		return nil, fmt.Errorf("start ffmpeg: %w", startError)
+	}
+
+	go func() {
+		if waitError := command.Wait(); waitError != nil {
+			log.Printf("hls: ffmpeg for stream %s exited: %v", streamID, waitError)
+		}
+	}()
+
+	manager := &Manager{
+		command:          command,
+		segmentDirectory: segmentDirectory,
+		lastAccess:       time.Now(),
+	}
+	handler.managers[streamID] = manager
+
+	return manager, nil
+}
+
+// reapIdleManagers periodically requests the close of managers that have exceeded the idle TTL.
+func (handler *Handler) reapIdleManagers() {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		handler.managersMutex.RLock()
+		var expiredStreamIDs []string
+		for streamID, manager := range handler.managers {
+			if manager.idleSince() > handler.idleTTL {
+				expiredStreamIDs = append(expiredStreamIDs, streamID)
+			}
+		}
+		handler.managersMutex.RUnlock()
+
+		for _, streamID := range expiredStreamIDs {
+			handler.closeRequests <- streamID
+		}
+	}
+}
+
+// consumeCloseRequests terminates and cleans up each manager whose streamID arrives on closeRequests.
+func (handler *Handler) consumeCloseRequests() {
+	for streamID := range handler.closeRequests {
+		handler.managersMutex.Lock()
+		manager, exists := handler.managers[streamID]
+		if exists {
+			delete(handler.managers, streamID)
+		}
+		handler.managersMutex.Unlock()
+
+		if !exists {
+			continue
+		}
+
+		if manager.command.Process != nil {
+			if killError := manager.command.Process.Kill(); killError != nil {
+				log.Printf("hls: failed to kill ffmpeg for stream %s: %v", streamID, killError)
+			}
+		}
+		if removeError := os.RemoveAll(manager.segmentDirectory); removeError != nil {
+			log.Printf("hls: failed to remove segment directory for stream %s: %v", streamID, removeError)
+		}
+		log.Printf("hls: reaped idle stream %s", streamID)
+	}
+}
+
+// waitForFile polls for path to exist, returning an error if timeout elapses first.
+func waitForFile(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, statError := os.Stat(path); statError == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		time.Sleep(chunkPollInterval)
+	}
+}
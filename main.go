@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"html/template"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -12,86 +16,44 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/temirov/web_video/internal/admin"
+	"github.com/temirov/web_video/internal/catalog"
+	"github.com/temirov/web_video/internal/events"
+	"github.com/temirov/web_video/internal/hls"
 )
 
-// Video represents a single video entry from the JSON file.
-type Video struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	FileName    string `json:"fileName"`
-}
+//go:embed templates
+var embeddedTemplatesDirectory embed.FS
 
-// PageData is the payload passed into the HTML template.
+// PageData is the payload passed into the HTML template. The video grid itself is
+// populated client-side from /videos/index.json, so this only carries page chrome
+// plus the HLSEnabled flag the client needs to decide whether to probe /hls/ at all.
 type PageData struct {
-	Title  string
-	Videos []Video
+	Title      string
+	HLSEnabled bool
 }
 
 const (
 	defaultServerAddress      = ":8080"
 	defaultVideosJSONFileName = "videos.json"
 	defaultStaticDirectory    = "static"
-	defaultTemplatesDirectory = "templates"
+	defaultTemplatesDirectory = "" // empty means use the templates embedded in the binary
 	defaultTitle              = "I am an American and I don’t give a fuck!"
+	defaultAdminUsername      = ""
+	defaultAdminPassword      = ""
+	defaultFFmpegPath         = "ffmpeg"
+	defaultHLSIdleTTL         = 60 * time.Second
+	defaultHLSMaxStreams      = 4
 )
 
-// loadAndValidateVideos reads the JSON file at the provided path, validates each entry,
-// and returns the sanitized slice of Video objects. Invalid or missing video files are skipped with a warning.
-func loadAndValidateVideos(videosJSONPath string, staticVideosDirectory string) ([]Video, error) {
-	contentBytes, readError := os.ReadFile(videosJSONPath)
-	if readError != nil {
-		return nil, readError
-	}
-
-	var rawVideos []Video
-	if unmarshalError := json.Unmarshal(contentBytes, &rawVideos); unmarshalError != nil {
-		return nil, unmarshalError
-	}
-
-	validatedVideos := make([]Video, 0, len(rawVideos))
-	for _, candidateVideo := range rawVideos {
-		if strings.TrimSpace(candidateVideo.FileName) == "" {
-			log.Printf("warning: skipping video with empty fileName: title=%q", candidateVideo.Title)
-			continue
-		}
-
-		baseFileName := filepath.Base(candidateVideo.FileName)
-		if baseFileName != candidateVideo.FileName {
-			log.Printf("warning: skipping video with disallowed path in fileName: %q", candidateVideo.FileName)
-			continue
-		}
-
-		candidatePath := filepath.Join(staticVideosDirectory, "videos", baseFileName)
-		if _, statError := os.Stat(candidatePath); os.IsNotExist(statError) {
-			log.Printf("warning: video file does not exist, skipping: %s", candidatePath)
-			continue
-		} else if statError != nil {
-			log.Printf("warning: unable to stat video file %s: %v (skipping)", candidatePath, statError)
-			continue
-		}
-
-		if strings.TrimSpace(candidateVideo.Title) == "" {
-			log.Printf("warning: skipping video with empty title for fileName=%q", candidateVideo.FileName)
-			continue
-		}
-		if strings.TrimSpace(candidateVideo.Description) == "" {
-			log.Printf("warning: skipping video with empty description for fileName=%q", candidateVideo.FileName)
-			continue
-		}
-
-		validatedVideos = append(validatedVideos, candidateVideo)
-	}
-
-	return validatedVideos, nil
-}
-
 // watchVideosJSON sets up a watcher on videosJSONPath. When the file changes, it reloads
-// and validates the list and swaps it into videoStore atomically. It debounces rapid consecutive events.
-func watchVideosJSON(videosJSONPath string, staticVideosDirectory string, videoStore *atomic.Value, watcherStartedSignal chan struct{}) {
+// and validates the list, swaps it into videoStore atomically, and broadcasts the change
+// over hub so connected SSE clients can refresh in place. It debounces rapid consecutive events.
+func watchVideosJSON(videosJSONPath string, staticVideosDirectory string, videoStore *catalog.Store, hub *events.Hub, watcherStartedSignal chan struct{}) {
 	fileWatcher, watcherError := fsnotify.NewWatcher()
 	if watcherError != nil {
 		log.Printf("error: failed to create fsnotify watcher: %v", watcherError)
@@ -114,13 +76,20 @@ func watchVideosJSON(videosJSONPath string, staticVideosDirectory string, videoS
 	var debounceTimer *time.Timer
 
 	triggerReload := func() {
-		reloadedVideos, loadError := loadAndValidateVideos(videosJSONPath, staticVideosDirectory)
+		reloadedVideos, loadError := catalog.LoadAndValidate(videosJSONPath, staticVideosDirectory)
 		if loadError != nil {
 			log.Printf("error: dynamic reload failed to load videos.json: %v", loadError)
 			return
 		}
 		videoStore.Store(reloadedVideos)
 		log.Printf("dynamic reload: updated videos list with %d validated video(s)", len(reloadedVideos))
+
+		encodedVideos, marshalError := json.Marshal(reloadedVideos)
+		if marshalError != nil {
+			log.Printf("error: failed to marshal videos for SSE broadcast: %v", marshalError)
+			return
+		}
+		hub.Broadcast(encodedVideos)
 	}
 
 	for {
@@ -155,64 +124,118 @@ func watchVideosJSON(videosJSONPath string, staticVideosDirectory string, videoS
 	}
 }
 
+// templatesFS returns the templates directory to render from: an on-disk override at
+// templatesDirectory if the flag was set, otherwise the templates embedded in the binary.
+func templatesFS(templatesDirectory string) (fs.FS, error) {
+	if templatesDirectory == "" {
+		return fs.Sub(embeddedTemplatesDirectory, "templates")
+	}
+	return os.DirFS(templatesDirectory), nil
+}
+
+// handleVideosIndexJSON serves the current validated video list as JSON with a strong
+// ETag computed over the encoded bytes, honoring If-None-Match with a 304 response.
+func handleVideosIndexJSON(videoStore *catalog.Store) http.HandlerFunc {
+	return func(responseWriter http.ResponseWriter, request *http.Request) {
+		encodedVideos, marshalError := json.Marshal(videoStore.Load())
+		if marshalError != nil {
+			http.Error(responseWriter, "Internal Server Error", http.StatusInternalServerError)
+			log.Printf("videos index: failed to marshal videos: %v", marshalError)
+			return
+		}
+
+		checksum := sha256.Sum256(encodedVideos)
+		etag := `"` + hex.EncodeToString(checksum[:]) + `"`
+
+		responseWriter.Header().Set("ETag", etag)
+		responseWriter.Header().Set("Cache-Control", "no-cache")
+
+		if request.Header.Get("If-None-Match") == etag {
+			responseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		responseWriter.Header().Set("Content-Type", "application/json")
+		if _, writeError := responseWriter.Write(encodedVideos); writeError != nil {
+			log.Printf("videos index: failed to write response: %v", writeError)
+		}
+	}
+}
+
 func main() {
 	serverAddressFlag := flag.String("address", defaultServerAddress, "address to listen on, e.g. :8080")
 	videosJSONPathFlag := flag.String("videos", defaultVideosJSONFileName, "path to videos.json")
 	staticDirectoryFlag := flag.String("static", defaultStaticDirectory, "static assets directory")
-	templatesDirectoryFlag := flag.String("templates", defaultTemplatesDirectory, "templates directory")
+	templatesDirectoryFlag := flag.String("templates", defaultTemplatesDirectory, "templates directory (defaults to the templates embedded in the binary)")
 	pageTitleFlag := flag.String("title", defaultTitle, "page title to display")
+	adminUsernameFlag := flag.String("admin-username", envOrDefault("WEB_VIDEO_ADMIN_USERNAME", defaultAdminUsername), "username required for /admin (also read from WEB_VIDEO_ADMIN_USERNAME)")
+	adminPasswordFlag := flag.String("admin-password", envOrDefault("WEB_VIDEO_ADMIN_PASSWORD", defaultAdminPassword), "password required for /admin (also read from WEB_VIDEO_ADMIN_PASSWORD)")
+	hlsEnabledFlag := flag.Bool("hls-enabled", false, "enable on-the-fly HLS transcoding at /hls/")
+	ffmpegPathFlag := flag.String("ffmpeg-path", defaultFFmpegPath, "path to the ffmpeg binary used for HLS transcoding")
+	hlsTTLFlag := flag.Duration("hls-ttl", defaultHLSIdleTTL, "idle time after which an HLS transcode is terminated")
+	hlsMaxStreamsFlag := flag.Int("hls-max-streams", defaultHLSMaxStreams, "maximum number of concurrent HLS transcodes")
+	rescanFlag := flag.Bool("rescan", false, "reconcile videos.json against static/videos/ on disk, then exit instead of serving")
 	flag.Parse()
 
 	staticDirectory := *staticDirectoryFlag
 	templatesDirectory := *templatesDirectoryFlag
 	videosJSONPath := *videosJSONPathFlag
 
+	if *rescanFlag {
+		reconciledVideos, rescanError := catalog.Rescan(videosJSONPath, staticDirectory)
+		if rescanError != nil {
+			log.Fatalf("Rescan failed: %v", rescanError)
+		}
+		log.Printf("Rescan complete: %d video(s) recorded in %s", len(reconciledVideos), videosJSONPath)
+		return
+	}
+
 	// Load and validate videos at startup.
-	initialVideoSlice, initialLoadError := loadAndValidateVideos(videosJSONPath, staticDirectory)
+	initialVideoSlice, initialLoadError := catalog.LoadAndValidate(videosJSONPath, staticDirectory)
 	if initialLoadError != nil {
 		log.Fatalf("Failed to load video metadata from %s: %v", videosJSONPath, initialLoadError)
 	}
 	log.Printf("Initial load: %d validated video(s).", len(initialVideoSlice))
 
 	// Prepare atomic store and put initial value.
-	var videoStore atomic.Value
-	videoStore.Store(initialVideoSlice)
+	videoStore := catalog.NewStore(initialVideoSlice)
+
+	// Hub for pushing catalog updates to connected SSE clients.
+	eventsHub := events.NewHub()
 
 	// Start watcher to dynamically reload on changes.
 	watcherReadyChannel := make(chan struct{})
-	go watchVideosJSON(videosJSONPath, staticDirectory, &videoStore, watcherReadyChannel)
+	go watchVideosJSON(videosJSONPath, staticDirectory, videoStore, eventsHub, watcherReadyChannel)
 	<-watcherReadyChannel // wait until watcher is initialized (or failed to initialize)
 
-	// Parse template once.
-	templatePath := filepath.Join(templatesDirectory, "index.html")
-	parsedTemplate, parseError := template.ParseFiles(templatePath)
+	// Parse templates once, either from the embedded FS or an on-disk override.
+	templatesDirectoryFS, templatesFSError := templatesFS(templatesDirectory)
+	if templatesFSError != nil {
+		log.Fatalf("Failed to open templates directory %s: %v", templatesDirectory, templatesFSError)
+	}
+	parsedTemplate, parseError := template.ParseFS(templatesDirectoryFS, "index.html")
 	if parseError != nil {
-		log.Fatalf("Failed to parse template %s: %v", templatePath, parseError)
+		log.Fatalf("Failed to parse index.html template: %v", parseError)
 	}
 
+	serveMux := http.NewServeMux()
+
 	// File server for static content.
 	fileServerHandler := http.FileServer(http.Dir(staticDirectory))
-	http.Handle("/static/", http.StripPrefix("/static/", fileServerHandler))
+	serveMux.Handle("/static/", http.StripPrefix("/static/", fileServerHandler))
 
 	// Health endpoint.
-	http.HandleFunc("/healthz", func(responseWriter http.ResponseWriter, request *http.Request) {
+	serveMux.HandleFunc("/healthz", func(responseWriter http.ResponseWriter, request *http.Request) {
 		responseWriter.WriteHeader(http.StatusOK)
 		_, _ = responseWriter.Write([]byte("ok"))
 	})
 
-	// Main handler.
-	http.HandleFunc("/", func(responseWriter http.ResponseWriter, request *http.Request) {
-		currentVideosInterface := videoStore.Load()
-		currentVideos, castOK := currentVideosInterface.([]Video)
-		if !castOK {
-			http.Error(responseWriter, "Internal Server Error", http.StatusInternalServerError)
-			log.Printf("type assertion failed on videoStore content")
-			return
-		}
-
+	// Main handler. The template itself fetches /videos/index.json client-side, so the
+	// page only needs the title here; the video grid renders and refreshes via script.
+	serveMux.HandleFunc("/", func(responseWriter http.ResponseWriter, request *http.Request) {
 		pageData := PageData{
-			Title:  *pageTitleFlag,
-			Videos: currentVideos,
+			Title:      *pageTitleFlag,
+			HLSEnabled: *hlsEnabledFlag,
 		}
 
 		executionError := parsedTemplate.Execute(responseWriter, pageData)
@@ -222,9 +245,39 @@ func main() {
 		}
 	})
 
+	// JSON feed of the validated catalog, with ETag-aware conditional serving.
+	serveMux.HandleFunc("/videos/index.json", handleVideosIndexJSON(videoStore))
+
+	// Server-Sent Events feed that pushes a videos-updated event on every catalog reload.
+	serveMux.Handle("/events", eventsHub)
+
+	// Admin subtree, guarded by Basic Auth, for uploading/deleting/renaming videos.
+	switch {
+	case *adminUsernameFlag != "" && *adminPasswordFlag != "":
+		adminHandler, adminHandlerError := admin.NewHandler(admin.Credentials{
+			Username: *adminUsernameFlag,
+			Password: *adminPasswordFlag,
+		}, staticDirectory, videosJSONPath, templatesDirectoryFS, videoStore)
+		if adminHandlerError != nil {
+			log.Fatalf("Failed to initialize admin handler: %v", adminHandlerError)
+		}
+		adminHandler.RegisterRoutes(serveMux)
+	case *adminUsernameFlag != "" || *adminPasswordFlag != "":
+		log.Fatalf("admin subsystem misconfigured: both -admin-username and -admin-password (or WEB_VIDEO_ADMIN_USERNAME / WEB_VIDEO_ADMIN_PASSWORD) must be set, not just one")
+	default:
+		log.Printf("admin subsystem disabled: set -admin-username and -admin-password (or WEB_VIDEO_ADMIN_USERNAME / WEB_VIDEO_ADMIN_PASSWORD) to enable it")
+	}
+
+	// HLS on-the-fly transcoding, alongside the raw /static/ file server.
+	if *hlsEnabledFlag {
+		hlsHandler := hls.NewHandler(staticDirectory, *ffmpegPathFlag, *hlsTTLFlag, *hlsMaxStreamsFlag)
+		serveMux.Handle("/hls/", http.StripPrefix("/hls/", hlsHandler))
+	}
+
 	// Build the HTTP server with timeouts.
 	httpServer := &http.Server{
 		Addr:         *serverAddressFlag,
+		Handler:      serveMux,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -261,3 +314,11 @@ func main() {
 	<-serverShutdownDoneChannel
 	log.Println("Server shutdown complete.")
 }
+
+// envOrDefault returns the value of the named environment variable, or fallbackValue if unset.
+func envOrDefault(environmentVariableName string, fallbackValue string) string {
+	if value, isSet := os.LookupEnv(environmentVariableName); isSet {
+		return value
+	}
+	return fallbackValue
+}